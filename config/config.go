@@ -0,0 +1,85 @@
+// Package config loads PartyUp's runtime settings from a YAML or JSON file
+// and merges them with command-line flags.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Filters controls which looking-for-party users are eligible for an invite.
+// Zero values mean "no restriction" except where noted.
+type Filters struct {
+	MinLvl     int    `yaml:"minLvl" json:"minLvl"`
+	Language   string `yaml:"language" json:"language"`
+	OnlyActive bool   `yaml:"onlyActive" json:"onlyActive"`
+}
+
+// Account describes one Habitica party PartyUp should invite users into.
+type Account struct {
+	Name    string  `yaml:"name" json:"name"`
+	APIUser string  `yaml:"apiUser" json:"apiUser"`
+	APIKey  string  `yaml:"apiKey" json:"apiKey"`
+	Filters Filters `yaml:"filters" json:"filters"`
+}
+
+// Config is the shape of the file passed via --config. A single account may
+// also be declared flat (apiUser/apiKey/filters at the top level) for users
+// who only run one party; it is folded into Accounts during Load.
+type Config struct {
+	LogLevel  string    `yaml:"logLevel" json:"logLevel"`
+	LogFormat string    `yaml:"logFormat" json:"logFormat"`
+	Accounts  []Account `yaml:"accounts" json:"accounts"`
+
+	// Flat single-account fields, folded into Accounts[0] when present.
+	APIUser string  `yaml:"apiUser" json:"apiUser"`
+	APIKey  string  `yaml:"apiKey" json:"apiKey"`
+	Filters Filters `yaml:"filters" json:"filters"`
+}
+
+// Load reads and parses the config file at path. The format is chosen by
+// file extension: .yaml/.yml is parsed as YAML, anything else as JSON.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config %q: %w", path, err)
+	}
+
+	var cfg Config
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".yaml" || ext == ".yml" {
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("parsing yaml config %q: %w", path, err)
+		}
+	} else {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("parsing json config %q: %w", path, err)
+		}
+	}
+
+	if cfg.APIUser != "" || cfg.APIKey != "" {
+		cfg.Accounts = append([]Account{{
+			Name:    "default",
+			APIUser: cfg.APIUser,
+			APIKey:  cfg.APIKey,
+			Filters: cfg.Filters,
+		}}, cfg.Accounts...)
+	}
+
+	if len(cfg.Accounts) == 0 {
+		return nil, fmt.Errorf("config %q declares no accounts", path)
+	}
+
+	for i, acc := range cfg.Accounts {
+		if acc.APIUser == "" || acc.APIKey == "" {
+			return nil, fmt.Errorf("account %d (%q) is missing apiUser/apiKey", i, acc.Name)
+		}
+	}
+
+	return &cfg, nil
+}
@@ -0,0 +1,181 @@
+// Package httpclient wraps net/http with the retry, backoff and rate-limit
+// handling Habitica's API expects from long-running clients.
+package httpclient
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// backoffBase and backoffCap bound the exponential backoff applied between
+// retries of a single request.
+const (
+	backoffBase = 500 * time.Millisecond
+	backoffCap  = 30 * time.Second
+)
+
+// Client retries transient failures (network errors and 5xx responses) with
+// exponential backoff, and honors Habitica's Retry-After and X-RateLimit-*
+// response headers so a single process never gets itself rate-limited.
+type Client struct {
+	HTTPClient http.Client
+	MaxRetries int
+	Log        zerolog.Logger
+
+	// OnRateLimit, if set, is called with the most recently observed
+	// X-RateLimit-Remaining value on every response that reports one.
+	OnRateLimit func(remaining int)
+}
+
+// New returns a Client ready to use. maxRetries is the number of additional
+// attempts made after an initial failure (0 disables retrying).
+func New(httpClient http.Client, maxRetries int, log zerolog.Logger) *Client {
+	return &Client{HTTPClient: httpClient, MaxRetries: maxRetries, Log: log}
+}
+
+// Do sends req, retrying on transient failures and waiting out any
+// rate-limit window Habitica reports. newReq is called with ctx to build a
+// fresh *http.Request for each attempt, since a Request's body can only be
+// read once. Canceling ctx aborts both in-flight requests and any wait
+// between retries.
+func (c *Client) Do(ctx context.Context, newReq func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	var lastErr error
+
+	for attempt := 0; attempt <= c.MaxRetries; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		req, err := newReq(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := c.HTTPClient.Do(req)
+		if err != nil {
+			lastErr = err
+			c.Log.Warn().Err(err).Int("attempt", attempt).Msg("request failed, will retry")
+			if err := c.sleep(ctx, backoff(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		remaining, reset, rateLimited := parseRateLimit(res.Header)
+		if rateLimited && c.OnRateLimit != nil {
+			c.OnRateLimit(remaining)
+		}
+
+		if retryAfter, ok := parseRetryAfter(res.Header.Get("Retry-After")); ok {
+			c.Log.Warn().Dur("retry_after", retryAfter).Int("status", res.StatusCode).Msg("honoring Retry-After header, will retry")
+			res.Body.Close()
+			lastErr = &StatusError{StatusCode: res.StatusCode}
+			if err := c.sleep(ctx, retryAfter); err != nil {
+				return nil, err
+			}
+			continue
+		} else if rateLimited {
+			if remaining == 0 {
+				wait := time.Until(reset)
+				c.Log.Warn().Time("reset", reset).Msg("rate limit exhausted, sleeping until reset")
+				if err := c.sleep(ctx, wait); err != nil {
+					res.Body.Close()
+					return nil, err
+				}
+			}
+		}
+
+		if res.StatusCode >= 500 {
+			res.Body.Close()
+			lastErr = &StatusError{StatusCode: res.StatusCode}
+			c.Log.Warn().Int("status", res.StatusCode).Int("attempt", attempt).Msg("server error, will retry")
+			if err := c.sleep(ctx, backoff(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		return res, nil
+	}
+
+	return nil, lastErr
+}
+
+// sleep waits for d, returning early with ctx.Err() if ctx is canceled
+// first.
+func (c *Client) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backoff computes sleep = min(cap, base * 2^attempt) + rand[0, base).
+func backoff(attempt int) time.Duration {
+	d := time.Duration(float64(backoffBase) * math.Pow(2, float64(attempt)))
+	if d > backoffCap {
+		d = backoffCap
+	}
+	return d + time.Duration(rand.Int63n(int64(backoffBase)))
+}
+
+// parseRetryAfter parses a Retry-After header, which is either a number of
+// seconds or an HTTP-date.
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if when, err := http.ParseTime(v); err == nil {
+		return time.Until(when), true
+	}
+	return 0, false
+}
+
+// parseRateLimit reads Habitica's X-RateLimit-Remaining and
+// X-RateLimit-Reset headers.
+func parseRateLimit(h http.Header) (remaining int, reset time.Time, ok bool) {
+	remStr := h.Get("X-RateLimit-Remaining")
+	resetStr := h.Get("X-RateLimit-Reset")
+	if remStr == "" || resetStr == "" {
+		return 0, time.Time{}, false
+	}
+
+	rem, err := strconv.Atoi(remStr)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+
+	if when, err := http.ParseTime(resetStr); err == nil {
+		return rem, when, true
+	}
+	if secs, err := strconv.ParseInt(resetStr, 10, 64); err == nil {
+		return rem, time.Unix(secs, 0), true
+	}
+
+	return 0, time.Time{}, false
+}
+
+// StatusError reports a non-2xx HTTP response that exhausted its retries.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return "habitica: server returned status " + strconv.Itoa(e.StatusCode)
+}
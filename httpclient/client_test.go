@@ -0,0 +1,107 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestDoRetriesAfterRetryAfterHeader(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := New(http.Client{}, 3, zerolog.Nop())
+
+	res, err := c.Do(context.Background(), func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	})
+	if err != nil {
+		t.Fatalf("Do returned error: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("final status = %d, want 200", res.StatusCode)
+	}
+	if requests != 2 {
+		t.Fatalf("requests = %d, want 2 (the 429 must be retried, not returned)", requests)
+	}
+}
+
+func TestParseRetryAfterSeconds(t *testing.T) {
+	d, ok := parseRetryAfter("2")
+	if !ok {
+		t.Fatal("expected ok=true for a numeric Retry-After")
+	}
+	if d.Seconds() != 2 {
+		t.Fatalf("parseRetryAfter(\"2\") = %v, want 2s", d)
+	}
+}
+
+func TestParseRetryAfterEmpty(t *testing.T) {
+	if _, ok := parseRetryAfter(""); ok {
+		t.Fatal("expected ok=false for an empty Retry-After")
+	}
+}
+
+func TestDoWithCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := New(http.Client{}, 3, zerolog.Nop())
+
+	called := false
+	_, err := c.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		called = true
+		return http.NewRequestWithContext(ctx, http.MethodGet, "http://example.invalid", nil)
+	})
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if called {
+		t.Fatal("newReq should not be called for an already-canceled context")
+	}
+}
+
+func TestDoCancelMidSleep(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := New(http.Client{}, 5, zerolog.Nop())
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := c.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	})
+	elapsed := time.Since(start)
+
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if elapsed > 2*time.Second {
+		t.Fatalf("Do took %v to return after cancellation, want well under the %v backoff cap", elapsed, backoffCap)
+	}
+}
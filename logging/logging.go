@@ -0,0 +1,38 @@
+// Package logging configures PartyUp's structured logger.
+package logging
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// New builds a zerolog.Logger writing to stderr. level is one of
+// debug/info/warn/error (case-insensitive, defaults to info). format is
+// either "json" (the zerolog default) or "text" for a human-readable
+// console writer.
+func New(level, format string) (zerolog.Logger, error) {
+	if level == "" {
+		level = "info"
+	}
+	lvl, err := zerolog.ParseLevel(strings.ToLower(level))
+	if err != nil {
+		return zerolog.Logger{}, fmt.Errorf("parsing log level %q: %w", level, err)
+	}
+
+	var writer = os.Stderr
+	logger := zerolog.New(writer).Level(lvl).With().Timestamp().Logger()
+
+	switch strings.ToLower(format) {
+	case "", "json":
+		// zerolog.New above already writes ND-JSON.
+	case "text":
+		logger = zerolog.New(zerolog.ConsoleWriter{Out: writer}).Level(lvl).With().Timestamp().Logger()
+	default:
+		return zerolog.Logger{}, fmt.Errorf("unknown log format %q (want json or text)", format)
+	}
+
+	return logger, nil
+}
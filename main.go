@@ -2,13 +2,24 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/TheMateo-ywh/Habitica-Auto-Invitar/config"
+	"github.com/TheMateo-ywh/Habitica-Auto-Invitar/httpclient"
+	"github.com/TheMateo-ywh/Habitica-Auto-Invitar/logging"
+	"github.com/TheMateo-ywh/Habitica-Auto-Invitar/metrics"
+	"github.com/TheMateo-ywh/Habitica-Auto-Invitar/store"
 )
 
 type Response struct {
@@ -45,6 +56,20 @@ type InviteRequest struct {
 	Uuids []string `json:"uuids"`
 }
 
+type PartyResponse struct {
+	Success bool `json:"success"`
+	Data    struct {
+		MemberCount int `json:"memberCount"`
+	} `json:"data"`
+}
+
+// batchResult tallies the outcome of one invite batch.
+type batchResult struct {
+	invited int
+	failed  int
+	skipped int
+}
+
 var apiUser string
 var apiKey string
 var minLvl int
@@ -53,6 +78,26 @@ var language string
 var onlyActive bool
 var maxCycles int
 var singleRun bool
+var configPath string
+var logLevel string
+var logFormat string
+var maxRetries int
+var storeURI string
+var reinviteAfter string
+var inviteBatchSize int
+var concurrency int
+var metricsAddr string
+var shutdownTimeout time.Duration
+
+// partyURL and inviteURL are package-level so tests can point them at a fake
+// server instead of the real Habitica API.
+var partyURL = "https://habitica.com/api/v3/groups/party"
+var inviteURL = "https://habitica.com/api/v3/groups/party/invite"
+
+// partyMaxMembers is Habitica's hard cap on party size.
+const partyMaxMembers = 30
+
+var log zerolog.Logger
 
 func main() {
 	flag.StringVar(&apiUser, "api-user", "", "Habitica API user")
@@ -63,153 +108,460 @@ func main() {
 	flag.BoolVar(&onlyActive, "only-active", false, "Only invite active users to party. Default is false.")
 	flag.IntVar(&maxCycles, "max-cycles", 1, "Number of cycles to run. Default is 1 (single run).")
 	flag.BoolVar(&singleRun, "single-run", false, "Run once and exit (overrides max-cycles). Default is false.")
+	flag.StringVar(&configPath, "config", "", "Path to a YAML or JSON config file. CLI flags take precedence over values it sets.")
+	flag.StringVar(&logLevel, "log-level", "info", "Log level: debug, info, warn or error. Default is info.")
+	flag.StringVar(&logFormat, "log-format", "json", "Log format: json or text. Default is json.")
+	flag.IntVar(&maxRetries, "max-retries", 5, "Max retries for transient HTTP failures. Default is 5.")
+	flag.StringVar(&storeURI, "store", "", "Invite ledger backend: file://path.db or redis://host:port. Default is no ledger (every cycle re-invites).")
+	flag.StringVar(&reinviteAfter, "reinvite-after", "7d", "Cooldown before a previously-invited user is eligible again. Default is 7d.")
+	flag.IntVar(&inviteBatchSize, "invite-batch-size", 10, "Number of uuids per invite request. Default is 10.")
+	flag.IntVar(&concurrency, "concurrency", 3, "Number of invite batches to dispatch concurrently. Default is 3.")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "Address to serve Prometheus /metrics and /healthz on, e.g. :9090. Default is disabled.")
+	flag.DurationVar(&shutdownTimeout, "shutdown-timeout", 30*time.Second, "Time to wait for an in-flight cycle to finish after SIGINT/SIGTERM before forcing exit. Default is 30s.")
 	flag.Parse()
 
-	if apiUser == "" || apiKey == "" {
-		log.Fatal("Please provide Habitica API user and key. (Use -api-user and -api-key flags)")
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	var err error
+	log, err = logging.New(logLevel, logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	accounts, err := resolveAccounts(set)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid configuration")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := httpclient.New(http.Client{Timeout: time.Second * 120}, maxRetries, log)
+
+	mx := metrics.New()
+	client.OnRateLimit = func(remaining int) { mx.RateLimitRemain.Set(float64(remaining)) }
+
+	if metricsAddr != "" {
+		go func() {
+			if err := mx.Serve(ctx, metricsAddr); err != nil {
+				log.Error().Err(err).Str("addr", metricsAddr).Msg("metrics server stopped")
+			}
+		}()
+		log.Info().Str("addr", metricsAddr).Msg("Serving /metrics and /healthz")
+	}
+
+	cooldown, err := store.ParseCooldown(reinviteAfter)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid --reinvite-after")
+	}
+
+	var ledger store.Store
+	if storeURI != "" {
+		ledger, err = store.Open(storeURI)
+		if err != nil {
+			log.Fatal().Err(err).Msg("opening invite ledger")
+		}
+		defer ledger.Close()
 	}
 
-	fmt.Println("Welcome to PartyUp! The script will now start fetching users and inviting them to party.")
-	
-	// Si single-run es true, ejecutar solo una vez
+	log.Info().Msg("Welcome to PartyUp! The script will now start fetching users and inviting them to party.")
+
 	if singleRun {
-		fmt.Println("Single-run mode: Executing one cycle...")
-		fetchUsersAndInvite()
-		fmt.Println("Single-run completed. Exiting.")
+		log.Info().Msg("Single-run mode: executing one cycle...")
+		runCycle(ctx, client, ledger, cooldown, accounts, 1, mx)
+		if ctx.Err() != nil {
+			log.Warn().Msg("Single-run interrupted by shutdown signal.")
+			os.Exit(1)
+		}
+		log.Info().Msg("Single-run completed. Exiting.")
 		return
 	}
-	
-	// Ejecutar en ciclos según maxCycles
+
 	if maxCycles <= 0 {
 		maxCycles = 1
 	}
-	
-	fmt.Printf("Running %d cycles with %d seconds interval...\n", maxCycles, fetchInterval)
-	
-	for i := 1; i <= maxCycles; i++ {
-		fmt.Printf("\n=== Cycle %d/%d ===\n", i, maxCycles)
-		fetchUsersAndInvite()
-		
-		// No esperar después del último ciclo
-		if i < maxCycles {
-			fmt.Printf("Waiting %d seconds for next cycle...\n", fetchInterval)
-			time.Sleep(time.Duration(fetchInterval) * time.Second)
+
+	log.Info().Int("cycles", maxCycles).Uint64("interval_seconds", fetchInterval).Msg("Running cycles")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= maxCycles; i++ {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Info().Int("cycle", i).Int("of", maxCycles).Msg("Starting cycle")
+			runCycle(ctx, client, ledger, cooldown, accounts, i, mx)
+
+			if i < maxCycles {
+				log.Info().Uint64("seconds", fetchInterval).Msg("Waiting for next cycle")
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Duration(fetchInterval) * time.Second):
+				}
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+		log.Info().Int("cycles", maxCycles).Msg("Completed all cycles. Exiting.")
+	case <-ctx.Done():
+		log.Info().Msg("Shutdown signal received, waiting for in-flight cycle to finish...")
+		select {
+		case <-done:
+			log.Info().Msg("Graceful shutdown complete.")
+		case <-time.After(shutdownTimeout):
+			log.Warn().Dur("timeout", shutdownTimeout).Msg("Shutdown timeout exceeded, forcing exit.")
+			os.Exit(1)
 		}
 	}
-	
-	fmt.Printf("\nCompleted all %d cycles. Exiting.\n", maxCycles)
 }
 
-func fetchUsersAndInvite() {
-	fmt.Println("Fetching users and inviting them to party...")
-	url := "https://habitica.com/api/v3/looking-for-party"
-
-	habiticaClient := http.Client{
-		Timeout: time.Second * 120,
+// resolveAccounts merges --config (if given) with CLI flags. Flags that were
+// explicitly set on the command line win over whatever the config declares;
+// this mirrors the precedence a urfave/cli app gets from ctx.IsSet.
+func resolveAccounts(set map[string]bool) ([]config.Account, error) {
+	if configPath == "" {
+		if apiUser == "" || apiKey == "" {
+			return nil, fmt.Errorf("please provide Habitica API user and key (use -api-user and -api-key flags, or -config)")
+		}
+		return []config.Account{{
+			Name:    "default",
+			APIUser: apiUser,
+			APIKey:  apiKey,
+			Filters: config.Filters{
+				MinLvl:     minLvl,
+				Language:   language,
+				OnlyActive: onlyActive,
+			},
+		}}, nil
 	}
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	cfg, err := config.Load(configPath)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	req.Header.Set("content-type", "application/json")
-	req.Header.Set("x-client", fmt.Sprintf("%s-PartyUp", apiUser))
-	req.Header.Set("x-api-user", apiUser)
-	req.Header.Set("x-api-key", apiKey)
+	effectiveLevel := logLevel
+	if !set["log-level"] && cfg.LogLevel != "" {
+		effectiveLevel = cfg.LogLevel
+	}
+	effectiveFormat := logFormat
+	if !set["log-format"] && cfg.LogFormat != "" {
+		effectiveFormat = cfg.LogFormat
+	}
+	if effectiveLevel != logLevel || effectiveFormat != logFormat {
+		if log, err = logging.New(effectiveLevel, effectiveFormat); err != nil {
+			return nil, err
+		}
+	}
 
-	res, getErr := habiticaClient.Do(req)
-	if getErr != nil {
-		log.Fatal(getErr)
+	accounts := cfg.Accounts
+	if set["api-user"] || set["api-key"] || set["min-lvl"] || set["language"] || set["only-active"] {
+		// Single-account CLI overrides apply to the first account only.
+		if set["api-user"] {
+			accounts[0].APIUser = apiUser
+		}
+		if set["api-key"] {
+			accounts[0].APIKey = apiKey
+		}
+		if set["min-lvl"] {
+			accounts[0].Filters.MinLvl = minLvl
+		}
+		if set["language"] {
+			accounts[0].Filters.Language = language
+		}
+		if set["only-active"] {
+			accounts[0].Filters.OnlyActive = onlyActive
+		}
 	}
 
-	if res.Body != nil {
-		defer res.Body.Close()
+	return accounts, nil
+}
+
+func runCycle(ctx context.Context, client *httpclient.Client, ledger store.Store, cooldown time.Duration, accounts []config.Account, cycle int, mx *metrics.Metrics) {
+	start := time.Now()
+	for _, acc := range accounts {
+		if ctx.Err() != nil {
+			break
+		}
+		fetchUsersAndInvite(ctx, client, ledger, cooldown, acc, cycle, mx)
 	}
+	mx.CycleDuration.Observe(time.Since(start).Seconds())
+	mx.LastCycleTime.SetToCurrentTime()
+}
+
+func fetchUsersAndInvite(ctx context.Context, client *httpclient.Client, ledger store.Store, cooldown time.Duration, acc config.Account, cycle int, mx *metrics.Metrics) {
+	log.Info().Str("account", acc.Name).Msg("Fetching users and inviting them to party...")
+	url := "https://habitica.com/api/v3/looking-for-party"
+
+	res, err := client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		setHabiticaHeaders(req, acc)
+		return req, nil
+	})
+	if err != nil {
+		log.Error().Err(err).Str("account", acc.Name).Msg("looking-for-party request failed, skipping this cycle")
+		return
+	}
+	defer res.Body.Close()
 
 	body, readErr := io.ReadAll(res.Body)
 	if readErr != nil {
-		log.Fatal(readErr)
+		log.Error().Err(readErr).Str("account", acc.Name).Msg("reading looking-for-party response failed")
+		return
 	}
 
 	var response Response
 	jsonErr := json.Unmarshal(body, &response)
 	if jsonErr != nil {
-		log.Fatal(jsonErr)
+		log.Error().Err(jsonErr).Str("account", acc.Name).Msg("parsing looking-for-party response failed")
+		return
 	}
 
 	if !response.Success {
-		log.Fatal("Request failed, please check your API user and key.")
+		log.Error().Str("account", acc.Name).Msg("request failed, please check your API user and key")
+		return
 	}
 
+	mx.UsersFetched.Add(float64(len(response.User)))
+
 	usersUuid := make([]string, 0)
 	for _, user := range response.User {
-		if isValidUser(user) {
-			usersUuid = append(usersUuid, user.Id)
+		if !isValidUser(user, acc.Filters) {
+			continue
 		}
+		if ledger != nil {
+			rec, found, err := ledger.Get(acc.Name, user.Id)
+			if err != nil {
+				log.Error().Err(err).Str("account", acc.Name).Str("user", user.Id).Msg("reading invite ledger failed, inviting anyway")
+			} else if found && !rec.Eligible(cooldown) {
+				continue
+			}
+		}
+		usersUuid = append(usersUuid, user.Id)
 	}
 
 	if len(usersUuid) != 0 {
-		fmt.Printf("Found %d valid users to invite.\n", len(usersUuid))
-		inviteUsers(habiticaClient, usersUuid)
+		log.Info().Str("account", acc.Name).Int("count", len(usersUuid)).Msg("Found valid users to invite")
+		inviteUsers(ctx, client, ledger, acc, usersUuid, cycle, mx)
 	} else {
-		fmt.Println("No users to invite at this time.")
+		log.Info().Str("account", acc.Name).Msg("No users to invite at this time")
+	}
+}
+
+// inviteUsers learns how many slots the party has left, trims uuids to fit,
+// chunks them into --invite-batch-size batches and dispatches those batches
+// through a worker pool of --concurrency workers.
+func inviteUsers(ctx context.Context, client *httpclient.Client, ledger store.Store, acc config.Account, uuids []string, cycle int, mx *metrics.Metrics) {
+	remaining, err := partySlotsRemaining(ctx, client, acc)
+	if err != nil {
+		log.Error().Err(err).Str("account", acc.Name).Msg("fetching party info failed, skipping invites")
+		return
+	}
+
+	if remaining <= 0 {
+		log.Info().Str("account", acc.Name).Msg("Party is full, skipping invites")
+		return
+	}
+
+	if len(uuids) > remaining {
+		log.Info().Str("account", acc.Name).Int("slots", remaining).Int("candidates", len(uuids)).
+			Msg("More candidates than party slots, trimming")
+		uuids = uuids[:remaining]
+	}
+
+	batches := chunkUuids(uuids, inviteBatchSize)
+
+	workers := concurrency
+	if workers <= 0 {
+		workers = 1
+	}
+	if workers > len(batches) {
+		workers = len(batches)
+	}
+
+	jobs := make(chan []string)
+	results := make(chan batchResult)
+
+	for w := 0; w < workers; w++ {
+		go func() {
+			for batch := range jobs {
+				results <- inviteBatch(ctx, client, ledger, acc, batch, cycle, mx)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, batch := range batches {
+			jobs <- batch
+		}
+	}()
+
+	var summary batchResult
+	for range batches {
+		r := <-results
+		summary.invited += r.invited
+		summary.failed += r.failed
+		summary.skipped += r.skipped
+	}
+
+	mx.UsersInvited.Add(float64(summary.invited))
+
+	log.Info().Str("account", acc.Name).
+		Int("invited", summary.invited).
+		Int("failed", summary.failed).
+		Int("skipped", summary.skipped).
+		Msg("Invite cycle summary")
+}
+
+// partySlotsRemaining looks up the party's current member count and returns
+// how many more members it can hold before hitting Habitica's cap.
+func partySlotsRemaining(ctx context.Context, client *httpclient.Client, acc config.Account) (int, error) {
+	res, err := client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, partyURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		setHabiticaHeaders(req, acc)
+		return req, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	var party PartyResponse
+	if err := json.Unmarshal(body, &party); err != nil {
+		return 0, err
+	}
+	if !party.Success {
+		return 0, fmt.Errorf("fetching party info failed")
 	}
+
+	return partyMaxMembers - party.Data.MemberCount, nil
 }
 
-func inviteUsers(client http.Client, uuids []string) {
-	inviteUrl := "https://habitica.com/api/v3/groups/party/invite"
+// chunkUuids splits uuids into batches of at most size.
+func chunkUuids(uuids []string, size int) [][]string {
+	if size <= 0 {
+		size = len(uuids)
+	}
 
+	var batches [][]string
+	for i := 0; i < len(uuids); i += size {
+		end := i + size
+		if end > len(uuids) {
+			end = len(uuids)
+		}
+		batches = append(batches, uuids[i:end])
+	}
+	return batches
+}
+
+// inviteBatch sends one invite request for a single batch of uuids and
+// records the ledger outcome for each.
+func inviteBatch(ctx context.Context, client *httpclient.Client, ledger store.Store, acc config.Account, uuids []string, cycle int, mx *metrics.Metrics) batchResult {
 	inviteRequest := InviteRequest{
 		Uuids: uuids,
 	}
 
 	inviteBody, jsonErr := json.Marshal(inviteRequest)
 	if jsonErr != nil {
-		log.Fatal(jsonErr)
+		log.Error().Err(jsonErr).Str("account", acc.Name).Msg("encoding invite request failed")
+		recordOutcome(ledger, acc.Name, uuids, cycle, store.OutcomeFailed)
+		mx.InviteErrors.WithLabelValues("encode").Inc()
+		return batchResult{failed: len(uuids)}
 	}
 
-	req, err := http.NewRequest(http.MethodPost, inviteUrl, bytes.NewBuffer(inviteBody))
+	res, err := client.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, inviteURL, bytes.NewBuffer(inviteBody))
+		if err != nil {
+			return nil, err
+		}
+		setHabiticaHeaders(req, acc)
+		return req, nil
+	})
 	if err != nil {
-		log.Fatal(err)
+		log.Error().Err(err).Str("account", acc.Name).Int("batch_size", len(uuids)).Msg("invite batch failed")
+		recordOutcome(ledger, acc.Name, uuids, cycle, store.OutcomeFailed)
+		mx.InviteErrors.WithLabelValues("request").Inc()
+		return batchResult{failed: len(uuids)}
 	}
+	defer res.Body.Close()
 
-	req.Header.Set("content-type", "application/json")
-	req.Header.Set("x-client", fmt.Sprintf("%s-PartyUp", apiUser))
-	req.Header.Set("x-api-user", apiUser)
-	req.Header.Set("x-api-key", apiKey)
+	body, readErr := io.ReadAll(res.Body)
+	if readErr != nil {
+		log.Error().Err(readErr).Str("account", acc.Name).Msg("reading invite response failed")
+		recordOutcome(ledger, acc.Name, uuids, cycle, store.OutcomeFailed)
+		mx.InviteErrors.WithLabelValues("read_response").Inc()
+		return batchResult{failed: len(uuids)}
+	}
 
-	res, postErr := client.Do(req)
-	if postErr != nil {
-		log.Fatal(postErr)
+	var response struct {
+		Success bool `json:"success"`
 	}
+	if err := json.Unmarshal(body, &response); err == nil && !response.Success {
+		log.Warn().Str("account", acc.Name).Int("batch_size", len(uuids)).Msg("invite batch rejected, likely already in party")
+		recordOutcome(ledger, acc.Name, uuids, cycle, store.OutcomeDeclined)
+		mx.InviteErrors.WithLabelValues("already_in_party").Inc()
+		return batchResult{skipped: len(uuids)}
+	}
+
+	log.Info().Str("account", acc.Name).Int("count", len(uuids)).Msg("Successfully invited batch")
+	recordOutcome(ledger, acc.Name, uuids, cycle, store.OutcomeInvited)
+	return batchResult{invited: len(uuids)}
+}
 
-	if res.Body != nil {
-		defer res.Body.Close()
+// recordOutcome updates the invite ledger for each uuid, if a ledger is
+// configured. Ledger failures are logged but never fail the cycle.
+func recordOutcome(ledger store.Store, account string, uuids []string, cycle int, outcome store.Outcome) {
+	if ledger == nil {
+		return
 	}
 
-	_, readErr := io.ReadAll(res.Body)
-	if readErr != nil {
-		log.Fatal(readErr)
+	for _, uuid := range uuids {
+		rec := store.Record{UserID: uuid, Cycle: cycle, Outcome: outcome, InvitedAt: time.Now()}
+		if err := ledger.Put(account, rec); err != nil {
+			log.Error().Err(err).Str("account", account).Str("user", uuid).Msg("updating invite ledger failed")
+		}
 	}
+}
 
-	fmt.Printf("Successfully invited %d users!\n", len(uuids))
+func setHabiticaHeaders(req *http.Request, acc config.Account) {
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("x-client", fmt.Sprintf("%s-PartyUp", acc.APIUser))
+	req.Header.Set("x-api-user", acc.APIUser)
+	req.Header.Set("x-api-key", acc.APIKey)
 }
 
-func isValidUser(user User) bool {
+func isValidUser(user User, filters config.Filters) bool {
 	if user.Id == "" {
 		return false
 	}
 
-	if user.Stats.Level < minLvl {
+	if user.Stats.Level < filters.MinLvl {
 		return false
 	}
 
-	if language != "" && user.Preferences.Language != language {
+	if filters.Language != "" && user.Preferences.Language != filters.Language {
 		return false
 	}
 
-	if onlyActive {
+	if filters.OnlyActive {
 		oneMonthAgo := time.Now().AddDate(0, -1, 0)
 		recently := time.Now().AddDate(0, 0, -4)
 
@@ -218,4 +570,4 @@ func isValidUser(user User) bool {
 	}
 
 	return true
-}
\ No newline at end of file
+}
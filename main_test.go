@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/TheMateo-ywh/Habitica-Auto-Invitar/config"
+	"github.com/TheMateo-ywh/Habitica-Auto-Invitar/httpclient"
+	"github.com/TheMateo-ywh/Habitica-Auto-Invitar/metrics"
+)
+
+// resetGlobals restores every package-level flag variable resolveAccounts
+// reads, so each test only has to set the ones it cares about.
+func resetGlobals() {
+	apiUser = ""
+	apiKey = ""
+	minLvl = 0
+	language = ""
+	onlyActive = false
+	configPath = ""
+	logLevel = "info"
+	logFormat = "json"
+}
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("writing test config: %v", err)
+	}
+	return path
+}
+
+func TestResolveAccountsCLIFlagsOnly(t *testing.T) {
+	resetGlobals()
+	defer resetGlobals()
+
+	apiUser = "cli-user"
+	apiKey = "cli-key"
+	minLvl = 10
+
+	accounts, err := resolveAccounts(map[string]bool{"api-user": true, "api-key": true, "min-lvl": true})
+	if err != nil {
+		t.Fatalf("resolveAccounts: %v", err)
+	}
+	if len(accounts) != 1 {
+		t.Fatalf("len(accounts) = %d, want 1", len(accounts))
+	}
+	if accounts[0].APIUser != "cli-user" || accounts[0].APIKey != "cli-key" || accounts[0].Filters.MinLvl != 10 {
+		t.Fatalf("unexpected account: %+v", accounts[0])
+	}
+}
+
+func TestResolveAccountsCLIFlagsMissing(t *testing.T) {
+	resetGlobals()
+	defer resetGlobals()
+
+	if _, err := resolveAccounts(map[string]bool{}); err == nil {
+		t.Fatal("expected an error when neither --config nor api-user/api-key are set")
+	}
+}
+
+func TestResolveAccountsConfigOnly(t *testing.T) {
+	resetGlobals()
+	defer resetGlobals()
+
+	configPath = writeConfig(t, `{
+		"apiUser": "cfg-user",
+		"apiKey": "cfg-key",
+		"filters": {"minLvl": 5, "language": "en"}
+	}`)
+
+	accounts, err := resolveAccounts(map[string]bool{})
+	if err != nil {
+		t.Fatalf("resolveAccounts: %v", err)
+	}
+	if len(accounts) != 1 {
+		t.Fatalf("len(accounts) = %d, want 1", len(accounts))
+	}
+	if accounts[0].APIUser != "cfg-user" || accounts[0].APIKey != "cfg-key" {
+		t.Fatalf("unexpected account credentials: %+v", accounts[0])
+	}
+	if accounts[0].Filters.MinLvl != 5 || accounts[0].Filters.Language != "en" {
+		t.Fatalf("unexpected account filters: %+v", accounts[0].Filters)
+	}
+}
+
+func TestResolveAccountsConfigWithOverrideOnFirstAccount(t *testing.T) {
+	resetGlobals()
+	defer resetGlobals()
+
+	configPath = writeConfig(t, `{
+		"accounts": [
+			{"name": "a1", "apiUser": "u1", "apiKey": "k1", "filters": {"minLvl": 1}},
+			{"name": "a2", "apiUser": "u2", "apiKey": "k2", "filters": {"minLvl": 2}}
+		]
+	}`)
+	apiUser = "override-user"
+	minLvl = 99
+
+	accounts, err := resolveAccounts(map[string]bool{"api-user": true, "min-lvl": true})
+	if err != nil {
+		t.Fatalf("resolveAccounts: %v", err)
+	}
+	if len(accounts) != 2 {
+		t.Fatalf("len(accounts) = %d, want 2", len(accounts))
+	}
+
+	if accounts[0].APIUser != "override-user" || accounts[0].Filters.MinLvl != 99 {
+		t.Fatalf("override did not apply to first account: %+v", accounts[0])
+	}
+	if accounts[0].APIKey != "k1" {
+		t.Fatalf("unset fields of the first account should be left alone: %+v", accounts[0])
+	}
+
+	if accounts[1].APIUser != "u2" || accounts[1].Filters.MinLvl != 2 {
+		t.Fatalf("override must not leak past the first account: %+v", accounts[1])
+	}
+}
+
+func TestChunkUuids(t *testing.T) {
+	uuids := []string{"a", "b", "c", "d", "e"}
+
+	cases := []struct {
+		name string
+		size int
+		want [][]string
+	}{
+		{"exact multiple", 1, [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+		{"remainder", 2, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}},
+		{"size <= 0 means one batch", 0, [][]string{{"a", "b", "c", "d", "e"}}},
+		{"negative size means one batch", -1, [][]string{{"a", "b", "c", "d", "e"}}},
+		{"size larger than input", 10, [][]string{{"a", "b", "c", "d", "e"}}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := chunkUuids(uuids, tc.size)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("chunkUuids(%v, %d) = %v, want %v", uuids, tc.size, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPartySlotsRemaining(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success":true,"data":{"memberCount":22}}`)
+	}))
+	defer srv.Close()
+
+	origPartyURL := partyURL
+	partyURL = srv.URL
+	defer func() { partyURL = origPartyURL }()
+
+	client := httpclient.New(http.Client{}, 0, zerolog.Nop())
+	remaining, err := partySlotsRemaining(context.Background(), client, config.Account{})
+	if err != nil {
+		t.Fatalf("partySlotsRemaining: %v", err)
+	}
+	if remaining != partyMaxMembers-22 {
+		t.Fatalf("remaining = %d, want %d", remaining, partyMaxMembers-22)
+	}
+}
+
+func TestInviteUsersTrimsToRemainingSlots(t *testing.T) {
+	partySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"success":true,"data":{"memberCount":28}}`)
+	}))
+	defer partySrv.Close()
+
+	var invited []string
+	inviteSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req InviteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding invite request: %v", err)
+		}
+		invited = req.Uuids
+		fmt.Fprint(w, `{"success":true}`)
+	}))
+	defer inviteSrv.Close()
+
+	origPartyURL, origInviteURL := partyURL, inviteURL
+	partyURL, inviteURL = partySrv.URL, inviteSrv.URL
+	defer func() { partyURL, inviteURL = origPartyURL, origInviteURL }()
+
+	origLog := log
+	log = zerolog.Nop()
+	defer func() { log = origLog }()
+
+	client := httpclient.New(http.Client{}, 0, zerolog.Nop())
+	mx := metrics.New()
+
+	uuids := []string{"u1", "u2", "u3", "u4", "u5"}
+	inviteUsers(context.Background(), client, nil, config.Account{Name: "acc"}, uuids, 1, mx)
+
+	want := partyMaxMembers - 28
+	if len(invited) != want {
+		t.Fatalf("invited %d uuids, want %d (party has %d slots left)", len(invited), want, want)
+	}
+	if !reflect.DeepEqual(invited, uuids[:want]) {
+		t.Fatalf("invited %v, want the first %d uuids %v", invited, want, uuids[:want])
+	}
+}
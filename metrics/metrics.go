@@ -0,0 +1,99 @@
+// Package metrics exposes PartyUp's Prometheus metrics and a /healthz
+// endpoint for long-running (cycle mode) deployments.
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics bundles every counter, gauge and histogram PartyUp reports.
+type Metrics struct {
+	UsersFetched    prometheus.Counter
+	UsersInvited    prometheus.Counter
+	InviteErrors    *prometheus.CounterVec
+	CycleDuration   prometheus.Histogram
+	LastCycleTime   prometheus.Gauge
+	RateLimitRemain prometheus.Gauge
+
+	registry *prometheus.Registry
+	server   *http.Server
+}
+
+// New creates and registers all PartyUp metrics on a fresh registry.
+func New() *Metrics {
+	registry := prometheus.NewRegistry()
+
+	m := &Metrics{
+		UsersFetched: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "partyup_users_fetched_total",
+			Help: "Total number of looking-for-party users fetched.",
+		}),
+		UsersInvited: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "partyup_users_invited_total",
+			Help: "Total number of users successfully invited to a party.",
+		}),
+		InviteErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "partyup_invite_errors_total",
+			Help: "Total number of invite failures, labeled by reason.",
+		}, []string{"reason"}),
+		CycleDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "partyup_cycle_duration_seconds",
+			Help: "Duration of a full fetch-and-invite cycle.",
+			// A cycle can spend tens of seconds to minutes in Retry-After/
+			// rate-limit/backoff waits, so this is scaled well above
+			// prometheus.DefBuckets' HTTP-latency range.
+			Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600},
+		}),
+		LastCycleTime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "partyup_last_cycle_timestamp",
+			Help: "Unix timestamp of the last completed cycle.",
+		}),
+		RateLimitRemain: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "partyup_ratelimit_remaining",
+			Help: "Most recently observed X-RateLimit-Remaining value.",
+		}),
+		registry: registry,
+	}
+
+	registry.MustRegister(
+		m.UsersFetched,
+		m.UsersInvited,
+		m.InviteErrors,
+		m.CycleDuration,
+		m.LastCycleTime,
+		m.RateLimitRemain,
+	)
+
+	return m
+}
+
+// Serve starts an HTTP server on addr exposing /metrics and /healthz. It
+// runs until ctx is canceled, then shuts down gracefully.
+func (m *Metrics) Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	m.server = &http.Server{Addr: addr, Handler: mux}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if err := m.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errCh <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return m.server.Shutdown(context.Background())
+	case err := <-errCh:
+		return err
+	}
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+)
+
+// fileStore persists the ledger in a single BoltDB file, one bucket per
+// account and one key per user UUID.
+type fileStore struct {
+	db *bbolt.DB
+}
+
+func newFileStore(path string) (Store, error) {
+	db, err := bbolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("opening bolt store %q: %w", path, err)
+	}
+	return &fileStore{db: db}, nil
+}
+
+func (s *fileStore) Get(account, userID string) (Record, bool, error) {
+	var rec Record
+	found := false
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(account))
+		if bucket == nil {
+			return nil
+		}
+		raw := bucket.Get([]byte(userID))
+		if raw == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(raw, &rec)
+	})
+	if err != nil {
+		return Record{}, false, fmt.Errorf("reading ledger entry for %s/%s: %w", account, userID, err)
+	}
+
+	return rec, found, nil
+}
+
+func (s *fileStore) Put(account string, rec Record) error {
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encoding ledger entry for %s: %w", rec.UserID, err)
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(account))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(rec.UserID), raw)
+	})
+}
+
+func (s *fileStore) Close() error {
+	return s.db.Close()
+}
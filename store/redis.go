@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisStore persists the ledger in Redis so several PartyUp instances can
+// share one view of who has already been invited.
+type redisStore struct {
+	client *redis.Client
+}
+
+func newRedisStore(u *url.URL) (Store, error) {
+	opts := &redis.Options{Addr: u.Host}
+	if u.User != nil {
+		opts.Password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		n, err := strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("parsing redis db %q: %w", db, err)
+		}
+		opts.DB = n
+	}
+
+	return &redisStore{client: redis.NewClient(opts)}, nil
+}
+
+func (s *redisStore) key(account, userID string) string {
+	return fmt.Sprintf("partyup:%s:%s", account, userID)
+}
+
+func (s *redisStore) Get(account, userID string) (Record, bool, error) {
+	raw, err := s.client.Get(context.Background(), s.key(account, userID)).Bytes()
+	if err == redis.Nil {
+		return Record{}, false, nil
+	}
+	if err != nil {
+		return Record{}, false, fmt.Errorf("reading ledger entry for %s/%s: %w", account, userID, err)
+	}
+
+	var rec Record
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return Record{}, false, fmt.Errorf("decoding ledger entry for %s/%s: %w", account, userID, err)
+	}
+
+	return rec, true, nil
+}
+
+func (s *redisStore) Put(account string, rec Record) error {
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encoding ledger entry for %s: %w", rec.UserID, err)
+	}
+
+	return s.client.Set(context.Background(), s.key(account, rec.UserID), raw, 0).Err()
+}
+
+func (s *redisStore) Close() error {
+	return s.client.Close()
+}
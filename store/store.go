@@ -0,0 +1,92 @@
+// Package store records which Habitica users PartyUp has already invited,
+// so later cycles can skip them until a cooldown expires.
+package store
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Outcome is the result of an invite attempt for a user.
+type Outcome string
+
+const (
+	OutcomeInvited  Outcome = "invited"
+	OutcomeDeclined Outcome = "declined"
+	OutcomeFailed   Outcome = "failed"
+)
+
+// Record is one entry in the invite ledger.
+type Record struct {
+	UserID    string    `json:"userId"`
+	Cycle     int       `json:"cycle"`
+	Outcome   Outcome   `json:"outcome"`
+	InvitedAt time.Time `json:"invitedAt"`
+}
+
+// Eligible reports whether a user last recorded with rec is eligible for a
+// fresh invite, given a reinviteAfter cooldown. A zero Record (not found) is
+// always eligible.
+func (rec Record) Eligible(reinviteAfter time.Duration) bool {
+	if rec.UserID == "" {
+		return true
+	}
+	if rec.Outcome != OutcomeInvited {
+		return true
+	}
+	return time.Since(rec.InvitedAt) >= reinviteAfter
+}
+
+// ParseCooldown parses a --reinvite-after value. It accepts anything
+// time.ParseDuration does, plus a "d" (day) suffix since Go's duration
+// parser stops at hours.
+func ParseCooldown(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("parsing reinvite-after %q: %w", s, err)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// Store is the ledger of invited users, keyed per account so the same
+// person can be tracked independently across parties.
+type Store interface {
+	// Get returns the most recent record for userID under account, and
+	// whether one was found.
+	Get(account, userID string) (Record, bool, error)
+	// Put records the outcome of an invite attempt.
+	Put(account string, rec Record) error
+	Close() error
+}
+
+// Open builds a Store from a URI: file://path.db (relative) or
+// file:///abs/path.db (absolute) selects the BoltDB-backed store,
+// redis://host:port[/db] selects the Redis-backed store.
+func Open(uri string) (Store, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("parsing store URI %q: %w", uri, err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		path := u.Opaque
+		if path == "" {
+			path = u.Host + u.Path
+		}
+		if path == "" {
+			return nil, fmt.Errorf("store URI %q has no path (want file://path.db or file:///abs/path.db)", uri)
+		}
+		return newFileStore(path)
+	case "redis":
+		return newRedisStore(u)
+	default:
+		return nil, fmt.Errorf("unsupported store scheme %q (want file:// or redis://)", u.Scheme)
+	}
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenFileURI(t *testing.T) {
+	dir := t.TempDir()
+
+	cases := []struct {
+		name string
+		uri  string
+	}{
+		{"two-slash prefix on an absolute path", "file://" + filepath.Join(dir, "ledger.db")},
+		{"absolute triple-slash form", "file:///" + filepath.Join(dir, "ledger2.db")[1:]},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := Open(tc.uri)
+			if err != nil {
+				t.Fatalf("Open(%q) returned error: %v", tc.uri, err)
+			}
+			defer s.Close()
+
+			if err := s.Put("acc", Record{UserID: "user-1"}); err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+			if _, found, err := s.Get("acc", "user-1"); err != nil || !found {
+				t.Fatalf("Get after Put: found=%v err=%v", found, err)
+			}
+		})
+	}
+}
+
+func TestOpenFileURINoPath(t *testing.T) {
+	if _, err := Open("file://"); err == nil {
+		t.Fatal("expected an error for a file:// URI with no path")
+	}
+}
+
+func TestParseCooldown(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"7d", "168h0m0s", false},
+		{"30m", "30m0s", false},
+		{"not-a-duration", "", true},
+	}
+
+	for _, tc := range cases {
+		got, err := ParseCooldown(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("ParseCooldown(%q): expected error, got nil", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseCooldown(%q): unexpected error: %v", tc.in, err)
+			continue
+		}
+		if got.String() != tc.want {
+			t.Errorf("ParseCooldown(%q) = %s, want %s", tc.in, got, tc.want)
+		}
+	}
+}